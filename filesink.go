@@ -0,0 +1,234 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileSinkOptions configures the rotation behavior of a RotatingFile.
+type FileSinkOptions struct {
+	// MaxBytes rotates the file once it has grown past this many bytes.
+	// Zero disables size-based rotation.
+	MaxBytes int64
+
+	// RotateEvery rotates the file once it has been open this long,
+	// regardless of size. Zero disables time-based rotation.
+	RotateEvery time.Duration
+
+	// MaxBackups is the number of rotated files to retain; older ones are
+	// removed after each rotation. Zero keeps them all.
+	MaxBackups int
+
+	// Gzip compresses each rotated file as it is created.
+	Gzip bool
+}
+
+// RotatingFile is an io.WriteCloser backed by a single log file that rotates
+// by size and/or age, in the style of logrotate. Create one with
+// NewFileSink and pass it to Init as the logFile argument: Init already
+// closes any logFile that satisfies io.Closer after Fatal, so a RotatingFile
+// is flushed and closed the same way a plain *os.File would be.
+//
+// Call Reopen from a SIGHUP handler to make RotatingFile cooperate with an
+// external log rotator: it flushes and reopens path, picking up whatever
+// file now lives there.
+type RotatingFile struct {
+	path string
+	opts FileSinkOptions
+
+	mu     sync.Mutex
+	file   *os.File
+	buf    *bufio.Writer
+	size   int64
+	opened time.Time
+}
+
+// NewFileSink opens (creating if necessary) path for appending and returns a
+// RotatingFile that writes to it, rotating according to opts.
+func NewFileSink(path string, opts FileSinkOptions) (*RotatingFile, error) {
+	rf := &RotatingFile{path: path, opts: opts}
+	if err := rf.openLocked(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *RotatingFile) openLocked() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file = f
+	rf.buf = bufio.NewWriter(f)
+	rf.size = info.Size()
+	rf.opened = time.Now()
+	return nil
+}
+
+// Write implements io.Writer, rotating first if this write would put the
+// file over MaxBytes or the file has been open longer than RotateEvery.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.shouldRotateLocked(int64(len(p))) {
+		if err := rf.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.buf.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *RotatingFile) shouldRotateLocked(next int64) bool {
+	if rf.opts.MaxBytes > 0 && rf.size+next > rf.opts.MaxBytes {
+		return true
+	}
+	if rf.opts.RotateEvery > 0 && time.Since(rf.opened) >= rf.opts.RotateEvery {
+		return true
+	}
+	return false
+}
+
+// rotateLocked flushes and closes the current file, renames it aside
+// (optionally gzip-compressing it), prunes backups past MaxBackups, and
+// opens a fresh file at path.
+func (rf *RotatingFile) rotateLocked() error {
+	if err := rf.closeFileLocked(); err != nil {
+		return err
+	}
+
+	backup := fmt.Sprintf("%s.%s", rf.path, time.Now().Format("20060102-150405.000000000"))
+	if err := os.Rename(rf.path, backup); err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+	} else if rf.opts.Gzip {
+		if err := gzipFile(backup); err != nil {
+			return err
+		}
+	}
+
+	if err := rf.pruneLocked(); err != nil {
+		return err
+	}
+
+	return rf.openLocked()
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	gw := gzip.NewWriter(dst)
+	if _, err := io.Copy(gw, src); err != nil {
+		gw.Close()
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// pruneLocked removes rotated backups of path beyond MaxBackups, oldest
+// first.
+func (rf *RotatingFile) pruneLocked() error {
+	if rf.opts.MaxBackups <= 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(rf.path + ".*")
+	if err != nil {
+		return err
+	}
+	if len(matches) <= rf.opts.MaxBackups {
+		return nil
+	}
+
+	sort.Strings(matches)
+	for _, m := range matches[:len(matches)-rf.opts.MaxBackups] {
+		os.Remove(m)
+	}
+	return nil
+}
+
+// Reopen flushes and closes the current file and opens path anew. It is
+// safe to call from a SIGHUP handler: an external tool like logrotate can
+// rename path out from under us, and Reopen picks up a fresh file in its
+// place without losing any buffered output.
+func (rf *RotatingFile) Reopen() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if err := rf.closeFileLocked(); err != nil {
+		return err
+	}
+	return rf.openLocked()
+}
+
+func (rf *RotatingFile) closeFileLocked() error {
+	if rf.buf != nil {
+		if err := rf.buf.Flush(); err != nil {
+			return err
+		}
+	}
+	if rf.file != nil {
+		return rf.file.Close()
+	}
+	return nil
+}
+
+// Flush writes any buffered output to the underlying file.
+func (rf *RotatingFile) Flush() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.buf == nil {
+		return nil
+	}
+	return rf.buf.Flush()
+}
+
+// Close flushes and closes the underlying file. Logger calls this
+// automatically after Fatal because RotatingFile satisfies io.Closer.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	return rf.closeFileLocked()
+}
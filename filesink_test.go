@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingFileSizeRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewFileSink(path, FileSinkOptions{MaxBytes: 10, MaxBackups: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := rf.Write([]byte("0123456789\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rf.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 {
+		t.Errorf("got %d backups, want 1 (MaxBackups should have pruned the rest): %v", len(matches), matches)
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	rf, err := NewFileSink(path, FileSinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+
+	rf.Write([]byte("before\n"))
+	rf.Flush()
+
+	if err := os.Rename(path, path+".rotated"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	rf.Write([]byte("after\n"))
+	rf.Flush()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "before") || !strings.Contains(string(b), "after") {
+		t.Errorf("reopened file contents = %q, want only \"after\\n\"", b)
+	}
+}
@@ -21,7 +21,10 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
 	"sync"
+	"time"
 )
 
 var (
@@ -29,17 +32,11 @@ var (
 	logLock       sync.Mutex
 )
 
-const (
-	flags    = log.Ldate | log.Lmicroseconds | log.Lshortfile
-	initText = "ERROR: Logging before logger.Init.\n"
-)
+const initText = "ERROR: Logging before logger.Init.\n"
 
 func initialize() {
-	defaultLogger = &Logger{
-		infoLog:  log.New(os.Stderr, initText+"INFO: ", flags),
-		errorLog: log.New(os.Stderr, initText+"ERROR: ", flags),
-		fatalLog: log.New(os.Stderr, initText+"FATAL: ", flags),
-	}
+	defaultLogger = &Logger{}
+	defaultLogger.AddSink(newWriterSink(os.Stderr), SeverityInfo)
 }
 
 func init() {
@@ -55,126 +52,122 @@ func init() {
 // If the logFile passed in also satisfy io.Closer, logFile.Close will be called
 // after calling Fatal or Fatalf.
 func Init(name string, verbose, systemLog bool, logFile io.Writer) *Logger {
-	var il, el io.Writer
+	var l Logger
+	l.AddSink(newClosableWriterSink(logFile), SeverityInfo)
+	l.AddSink(newWriterSink(os.Stderr), SeverityError)
+	if verbose {
+		l.AddSink(onlySink{sink: newWriterSink(os.Stdout), sev: SeverityInfo}, SeverityInfo)
+	}
+
 	if systemLog {
-		var err error
-		il, el, err = setup(name)
+		il, el, fl, err := setup(name)
 		if err != nil {
 			log.Fatal(err)
 		}
+		l.AddSink(onlySink{sink: newWriterSink(il), sev: SeverityInfo}, SeverityInfo)
+		l.AddSink(onlySink{sink: newWriterSink(el), sev: SeverityError}, SeverityError)
+		l.AddSink(onlySink{sink: newWriterSink(fl), sev: SeverityFatal}, SeverityFatal)
 	}
 
-	iLogs := []io.Writer{logFile}
-	eLogs := []io.Writer{logFile, os.Stderr}
-	if verbose {
-		iLogs = append(iLogs, os.Stdout)
-	}
-	if il != nil {
-		iLogs = append(iLogs, il)
-	}
-	if el != nil {
-		eLogs = append(eLogs, el)
-	}
-
-	var l Logger
-	l.infoLog = log.New(io.MultiWriter(iLogs...), "INFO: ", flags)
-	l.errorLog = log.New(io.MultiWriter(eLogs...), "ERROR: ", flags)
-	l.fatalLog = log.New(io.MultiWriter(eLogs...), "FATAL: ", flags)
-	if c, ok := logFile.(io.Closer); ok {
-		l.closers = append(l.closers, c)	
-	}
 	l.initialized = true
 
 	logLock.Lock()
-	defer logLock.Unlock()
 	if !defaultLogger.initialized {
 		defaultLogger = &l
 	}
+	logLock.Unlock()
 
 	return &l
 }
 
-type severity int
-
-const (
-	sInfo = iota
-	sError
-	sFatal
-)
-
 // A Logger represents an active logging object. Multiple loggers can be used
 // simultaneously even if they are using the same same writers.
 type Logger struct {
-	infoLog     *log.Logger
-	errorLog    *log.Logger
-	fatalLog    *log.Logger
-	closers     []io.Closer
+	mu          sync.Mutex
+	sinks       []sinkBinding
 	initialized bool
+	structured  bool
+	kv          []interface{}
 }
 
-func (l *Logger) output(s severity, txt string) {
-	logLock.Lock()
-	defer logLock.Unlock()
-	switch s {
-	case sInfo:
-		l.infoLog.Output(3, txt)
-	case sError:
-		l.errorLog.Output(3, txt)
-	case sFatal:
-		l.fatalLog.Output(3, txt)
-	default:
-		panic(fmt.Sprintln("unrecognized severity:", s))
+func (l *Logger) output(s Severity, txt string) {
+	_, file, line, ok := runtime.Caller(2)
+	if !ok {
+		file, line = "???", 0
+	} else {
+		file = filepath.Base(file)
+	}
+	ts := time.Now()
+
+	l.mu.Lock()
+	sinks := l.sinks
+	l.mu.Unlock()
+
+	initialized := l.initialized
+	if !initialized {
+		txt = initText + txt
+	}
+
+	for _, b := range sinks {
+		if s < b.min {
+			continue
+		}
+		if err := b.sink.Write(s, ts, file, line, txt); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: sink write failed: %v\n", err)
+		}
 	}
 }
 
 func (l *Logger) close() {
-	logLock.Lock()
-	defer logLock.Unlock()
-	for _, c := range l.closers {
-		c.Close()
+	l.mu.Lock()
+	sinks := l.sinks
+	l.mu.Unlock()
+	for _, b := range sinks {
+		b.sink.Flush()
+		b.sink.Close()
 	}
 }
 
 // Info logs with the INFO severity.
 // Arguments are handled in the manner of fmt.Print.
 func (l *Logger) Info(v ...interface{}) {
-	l.output(sInfo, fmt.Sprint(v...))
+	l.output(SeverityInfo, fmt.Sprint(v...))
 }
 
 // Infoln logs with the INFO severity.
 // Arguments are handled in the manner of fmt.Println.
 func (l *Logger) Infoln(v ...interface{}) {
-	l.output(sInfo, fmt.Sprintln(v...))
+	l.output(SeverityInfo, fmt.Sprintln(v...))
 }
 
 // Infof logs with the INFO severity.
 // Arguments are handled in the manner of fmt.Printf.
 func (l *Logger) Infof(format string, v ...interface{}) {
-	l.output(sInfo, fmt.Sprintf(format, v...))
+	l.output(SeverityInfo, fmt.Sprintf(format, v...))
 }
 
 // Error logs with the ERROR severity.
 // Arguments are handled in the manner of fmt.Print.
 func (l *Logger) Error(v ...interface{}) {
-	l.output(sError, fmt.Sprint(v...))
+	l.output(SeverityError, fmt.Sprint(v...))
 }
 
 // Errorln logs with the ERROR severity.
 // Arguments are handled in the manner of fmt.Println.
 func (l *Logger) Errorln(v ...interface{}) {
-	l.output(sError, fmt.Sprintln(v...))
+	l.output(SeverityError, fmt.Sprintln(v...))
 }
 
 // Errorf logs with the Error severity.
 // Arguments are handled in the manner of fmt.Printf.
 func (l *Logger) Errorf(format string, v ...interface{}) {
-	l.output(sError, fmt.Sprintf(format, v...))
+	l.output(SeverityError, fmt.Sprintf(format, v...))
 }
 
 // Fatal logs with the Fatal severity, and ends with os.Exit(1).
 // Arguments are handled in the manner of fmt.Print.
 func (l *Logger) Fatal(v ...interface{}) {
-	l.output(sFatal, fmt.Sprint(v...))
+	l.output(SeverityFatal, fmt.Sprint(v...))
 	l.close()
 	os.Exit(1)
 }
@@ -182,7 +175,7 @@ func (l *Logger) Fatal(v ...interface{}) {
 // Fatalln logs with the Fatal severity, and ends with os.Exit(1).
 // Arguments are handled in the manner of fmt.Println.
 func (l *Logger) Fatalln(v ...interface{}) {
-	l.output(sFatal, fmt.Sprintln(v...))
+	l.output(SeverityFatal, fmt.Sprintln(v...))
 	l.close()
 	os.Exit(1)
 }
@@ -190,7 +183,7 @@ func (l *Logger) Fatalln(v ...interface{}) {
 // Fatalf logs with the Fatal severity, and ends with os.Exit(1).
 // Arguments are handled in the manner of fmt.Printf.
 func (l *Logger) Fatalf(format string, v ...interface{}) {
-	l.output(sFatal, fmt.Sprintf(format, v...))
+	l.output(SeverityFatal, fmt.Sprintf(format, v...))
 	l.close()
 	os.Exit(1)
 }
@@ -198,44 +191,44 @@ func (l *Logger) Fatalf(format string, v ...interface{}) {
 // Info uses the default logger and logs with the Info severity.
 // Arguments are handled in the manner of fmt.Print.
 func Info(v ...interface{}) {
-	defaultLogger.output(sInfo, fmt.Sprint(v...))
+	defaultLogger.output(SeverityInfo, fmt.Sprint(v...))
 }
 
 // Infoln uses the default logger and logs with the Info severity.
 // Arguments are handled in the manner of fmt.Println.
 func Infoln(v ...interface{}) {
-	defaultLogger.output(sInfo, fmt.Sprintln(v...))
+	defaultLogger.output(SeverityInfo, fmt.Sprintln(v...))
 }
 
 // Infof uses the default logger and logs with the Info severity.
 // Arguments are handled in the manner of fmt.Printf.
 func Infof(format string, v ...interface{}) {
-	defaultLogger.output(sInfo, fmt.Sprintf(format, v...))
+	defaultLogger.output(SeverityInfo, fmt.Sprintf(format, v...))
 }
 
 // Error uses the default logger and logs with the Error severity.
 // Arguments are handled in the manner of fmt.Print.
 func Error(v ...interface{}) {
-	defaultLogger.output(sError, fmt.Sprint(v...))
+	defaultLogger.output(SeverityError, fmt.Sprint(v...))
 }
 
 // Errorln uses the default logger and logs with the Error severity.
 // Arguments are handled in the manner of fmt.Println.
 func Errorln(v ...interface{}) {
-	defaultLogger.output(sError, fmt.Sprintln(v...))
+	defaultLogger.output(SeverityError, fmt.Sprintln(v...))
 }
 
 // Errorf uses the default logger and logs with the Error severity.
 // Arguments are handled in the manner of fmt.Printf.
 func Errorf(format string, v ...interface{}) {
-	defaultLogger.output(sError, fmt.Sprintf(format, v...))
+	defaultLogger.output(SeverityError, fmt.Sprintf(format, v...))
 }
 
 // Fatalln uses the default logger, logs with the Fatal severity,
 // and ends with os.Exit(1).
 // Arguments are handled in the manner of fmt.Print.
 func Fatal(v ...interface{}) {
-	defaultLogger.output(sFatal, fmt.Sprint(v...))
+	defaultLogger.output(SeverityFatal, fmt.Sprint(v...))
 	defaultLogger.close()
 	os.Exit(1)
 }
@@ -244,7 +237,7 @@ func Fatal(v ...interface{}) {
 // and ends with os.Exit(1).
 // Arguments are handled in the manner of fmt.Println.
 func Fatalln(v ...interface{}) {
-	defaultLogger.output(sFatal, fmt.Sprintln(v...))
+	defaultLogger.output(SeverityFatal, fmt.Sprintln(v...))
 	defaultLogger.close()
 	os.Exit(1)
 }
@@ -253,7 +246,7 @@ func Fatalln(v ...interface{}) {
 // and ends with os.Exit(1).
 // Arguments are handled in the manner of fmt.Printf.
 func Fatalf(format string, v ...interface{}) {
-	defaultLogger.output(sFatal, fmt.Sprintf(format, v...))
+	defaultLogger.output(SeverityFatal, fmt.Sprintf(format, v...))
 	defaultLogger.close()
 	os.Exit(1)
 }
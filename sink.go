@@ -0,0 +1,150 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Severity identifies how serious a log record is.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityError
+	SeverityFatal
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "INFO"
+	case SeverityError:
+		return "ERROR"
+	case SeverityFatal:
+		return "FATAL"
+	default:
+		return fmt.Sprintf("SEVERITY(%d)", int(s))
+	}
+}
+
+// Sink is a logging destination. A Logger fans every record out to each of
+// its registered sinks that was registered with a minimum severity at or
+// below the record's severity; see Logger.AddSink.
+//
+// Write, Flush and Close may be called concurrently with themselves and
+// with each other and must be safe for that; Logger does not serialize
+// calls into a sink beyond the ordering of records it was itself given.
+type Sink interface {
+	// Write emits one log record.
+	Write(sev Severity, ts time.Time, file string, line int, msg string) error
+
+	// Flush writes any buffered records to their destination.
+	Flush() error
+
+	// Close releases any resources held by the sink. A Logger calls Close
+	// on all of its sinks after a Fatal log.
+	Close() error
+}
+
+// sinkBinding pairs a Sink with the minimum severity it wants to see.
+type sinkBinding struct {
+	sink Sink
+	min  Severity
+}
+
+// AddSink registers sink to receive every record logged at severity min or
+// above. Sinks are written to in registration order.
+func (l *Logger) AddSink(sink Sink, min Severity) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sinks = append(l.sinks, sinkBinding{sink: sink, min: min})
+}
+
+// writerSink formats records as text, in the style this package has always
+// used (e.g. "INFO: 2009/01/23 01:23:23.123123 file.go:42: message"), and
+// writes them to an io.Writer.
+type writerSink struct {
+	mu       sync.Mutex
+	w        io.Writer
+	closable bool
+}
+
+// newWriterSink wraps w without ever closing it, for destinations such as
+// os.Stdout and os.Stderr that the Logger does not own.
+func newWriterSink(w io.Writer) *writerSink {
+	return &writerSink{w: w}
+}
+
+// newClosableWriterSink wraps w and closes it, if it implements io.Closer,
+// when the sink is closed. Used for caller-supplied destinations such as a
+// log file that the Logger is responsible for flushing on Fatal.
+func newClosableWriterSink(w io.Writer) *writerSink {
+	return &writerSink{w: w, closable: true}
+}
+
+func (s *writerSink) Write(sev Severity, ts time.Time, file string, line int, msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Like log.Logger, add a newline only if msg doesn't already end in one.
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+	_, err := fmt.Fprintf(s.w, "%s: %s %s:%d: %s\n", sev, ts.Format("2006/01/02 15:04:05.000000"), file, line, msg)
+	return err
+}
+
+func (s *writerSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+func (s *writerSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closable {
+		return nil
+	}
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// onlySink restricts an underlying sink to a single severity. It exists so
+// Init can reproduce this package's historical, non-cascading routing (the
+// verbose stdout mirror only ever carried INFO; it never duplicated errors
+// that were already going to stderr) on top of AddSink's cascading minimum
+// severity model.
+type onlySink struct {
+	sink Sink
+	sev  Severity
+}
+
+func (o onlySink) Write(sev Severity, ts time.Time, file string, line int, msg string) error {
+	if sev != o.sev {
+		return nil
+	}
+	return o.sink.Write(sev, ts, file, line, msg)
+}
+
+func (o onlySink) Flush() error { return o.sink.Flush() }
+func (o onlySink) Close() error { return o.sink.Close() }
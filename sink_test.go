@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingSink struct {
+	records []string
+}
+
+func (r *recordingSink) Write(sev Severity, ts time.Time, file string, line int, msg string) error {
+	r.records = append(r.records, sev.String()+": "+msg)
+	return nil
+}
+
+func (r *recordingSink) Flush() error { return nil }
+func (r *recordingSink) Close() error { return nil }
+
+func TestAddSinkSeverityThreshold(t *testing.T) {
+	var buf bytes.Buffer
+	l := Init("testAddSink", false, false, &buf)
+
+	rec := &recordingSink{}
+	l.AddSink(rec, SeverityError)
+
+	l.Info("info message")
+	l.Error("error message")
+
+	if len(rec.records) != 1 || !strings.Contains(rec.records[0], "error message") {
+		t.Errorf("sink registered at SeverityError got %v, want only the error record", rec.records)
+	}
+}
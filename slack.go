@@ -11,17 +11,15 @@ See the License for the specific language governing permissions and
 limitations under the License.
 */
 
-// Package logger offers simple cross platform logging for Windows and Linux.
-// Available logging endpoints are event log (Windows), syslog (Linux), and
-// an io.Writer.
 package logger
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
-
-	"github.com/parnurzeal/gorequest"
+	"time"
 )
 
 // Slack colors for messages
@@ -31,53 +29,156 @@ const (
 	ColorWarning = "warning"
 )
 
-// SendAlert sends a notification to the specified slack channel
-func SendAlert(channel, username, title, color, text string) (err error) {
-
-	if channel != "" {
-
-		// control parameters are valid
-		if color == "" {
-			color = ColorGood
-		}
-
-		template := `
-		{
-			"username": "$USERNAME",
-			"attachments": [
-				{
-					"title": "$TITLE",
-					"color": "$COLOR",
-					"text": "$TEXT"
-				}
-			]
-		}
-		`
-
-		// replace custom data
-		msg := strings.Replace(template, "$USERNAME", username, 1)
-		msg = strings.Replace(msg, "$TITLE", title, 1)
-		msg = strings.Replace(msg, "$COLOR", color, 1)
-		msg = strings.Replace(msg, "$TEXT", text, 1)
-
-		// send message using HTTP
-		agent := gorequest.New()
-
-		response, _, errPost := agent.Post(channel).Send(msg).End()
-		if response != nil {
-			defer response.Body.Close()
-		}
-
-		if errPost == nil {
-			if response.StatusCode != http.StatusOK {
-				err = fmt.Errorf("Slack returned status %s", response.Status)
-			}
-		} else {
-			err = fmt.Errorf("Error sending message to Slack: %s", errPost[0])
-		}
-	} else {
-		err = fmt.Errorf("Invalid channel")
+// SlackOptions configures a SlackSink or SlackAlerter.
+type SlackOptions struct {
+	// Username is the bot name attached to posted messages.
+	Username string
+
+	// Colors maps severity to a Slack attachment color. Severities missing
+	// from the map fall back to ColorGood (INFO), ColorWarning (ERROR) or
+	// ColorDanger (FATAL). Pass a non-nil map to override individual
+	// entries without losing the rest of the defaults.
+	Colors map[Severity]string
+
+	// QueueSize bounds how many pending messages a SlackAlerter (see
+	// NewSlackAlerter) will hold; beyond this, messages are dropped and
+	// counted in SlackAlerter.Errors. Zero uses a default of 256. Ignored
+	// by NewSlackSink.
+	QueueSize int
+
+	// CoalesceWindow makes a SlackAlerter wait this long after its first
+	// queued message for more to arrive, posting the whole burst as one
+	// Slack message with one attachment per call instead of one API call
+	// per call. Zero disables the wait: only messages already queued when a
+	// post begins are sent together. Ignored by NewSlackSink.
+	CoalesceWindow time.Duration
+}
+
+var defaultSlackClient = &http.Client{Timeout: 10 * time.Second}
+
+// attachment is one Slack message attachment.
+type attachment struct {
+	Title string `json:"title"`
+	Color string `json:"color"`
+	Text  string `json:"text"`
+}
+
+// slackRateLimitError reports that Slack answered a post with 429 Too Many
+// Requests, and how long it asked callers to wait before retrying.
+type slackRateLimitError struct {
+	retryAfter time.Duration
+}
+
+func (e *slackRateLimitError) Error() string {
+	return fmt.Sprintf("logger: Slack rate limited, retry after %s", e.retryAfter)
+}
+
+func parseRetryAfter(v string) time.Duration {
+	var secs int
+	if _, err := fmt.Sscanf(v, "%d", &secs); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return time.Second
+}
+
+// SlackSink posts log records to a Slack channel via an incoming webhook. It
+// implements Sink, so register it with Logger.AddSink and a minimum
+// severity (typically SeverityError) to have errors and fatals posted to
+// Slack automatically instead of requiring an explicit SendAlert call next
+// to every Errorf:
+//
+//	l.AddSink(logger.NewSlackSink(webhookURL, logger.SlackOptions{Username: "myapp"}), logger.SeverityError)
+//
+// Write posts synchronously, so a Slack outage or throttling stalls
+// whatever goroutine is logging. NewSlackAlerter is an async alternative
+// for hot paths.
+type SlackSink struct {
+	channel  string
+	username string
+	colors   map[Severity]string
+	client   *http.Client
+}
+
+// NewSlackSink returns a SlackSink that posts to the given incoming webhook
+// URL.
+func NewSlackSink(channel string, opts SlackOptions) *SlackSink {
+	colors := map[Severity]string{
+		SeverityInfo:  ColorGood,
+		SeverityError: ColorWarning,
+		SeverityFatal: ColorDanger,
+	}
+	for sev, color := range opts.Colors {
+		colors[sev] = color
+	}
+	return &SlackSink{channel: channel, username: opts.Username, colors: colors}
+}
+
+// Write implements Sink by posting msg as a Slack attachment titled with
+// sev, file and line.
+func (s *SlackSink) Write(sev Severity, ts time.Time, file string, line int, msg string) error {
+	title := fmt.Sprintf("%s: %s:%d", sev, file, line)
+	return s.post(title, s.colors[sev], msg)
+}
+
+// Flush is a no-op; SlackSink has nothing to buffer.
+func (s *SlackSink) Flush() error { return nil }
+
+// Close is a no-op; SlackSink holds no resources to release.
+func (s *SlackSink) Close() error { return nil }
+
+func (s *SlackSink) post(title, color, text string) error {
+	if color == "" {
+		color = ColorGood
+	}
+	return s.postAttachments(context.Background(), []attachment{{Title: title, Color: color, Text: text}})
+}
+
+// postAttachments sends one Slack message carrying all of attachments. It
+// returns a *slackRateLimitError if Slack answers with 429, so callers that
+// want to retry (see SlackAlerter) can back off for as long as Slack asked.
+func (s *SlackSink) postAttachments(ctx context.Context, attachments []attachment) error {
+	if s.channel == "" {
+		return fmt.Errorf("logger: invalid Slack channel")
+	}
+
+	body, err := json.Marshal(struct {
+		Username    string       `json:"username,omitempty"`
+		Attachments []attachment `json:"attachments"`
+	}{Username: s.username, Attachments: attachments})
+	if err != nil {
+		return fmt.Errorf("logger: encoding Slack message: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.channel, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("logger: building Slack request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.client
+	if client == nil {
+		client = defaultSlackClient
 	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("logger: sending message to Slack: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &slackRateLimitError{retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("logger: Slack returned status %s", resp.Status)
+	}
+	return nil
+}
 
-	return
+// SendAlert sends a one-off notification to the given Slack channel. Most
+// callers logging through a Logger should prefer registering a SlackSink or
+// SlackAlerter with AddSink so errors are posted automatically; SendAlert
+// remains for call sites that want to alert outside the regular logging
+// path.
+func SendAlert(channel, username, title, color, text string) error {
+	return (&SlackSink{channel: channel, username: username}).post(title, color, text)
 }
@@ -0,0 +1,229 @@
+/*
+Copyright 2018 AstroPay LLC. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SlackAlerter is an async, batching alternative to SlackSink. Write
+// enqueues onto a bounded channel and returns immediately; a background
+// goroutine posts to Slack, retrying with exponential backoff when Slack
+// answers 429 with a Retry-After header. Messages that arrive within
+// opts.CoalesceWindow of the first message of a batch are merged into a
+// single post with one attachment per message. Messages that arrive once the queue is full are
+// dropped and counted in Errors, rather than blocking the caller.
+//
+// Close stops accepting new messages, waits for the queue to drain, and
+// returns once the background goroutine has exited.
+type SlackAlerter struct {
+	sink     *SlackSink
+	queue    chan attachment
+	done     chan struct{}
+	coalesce time.Duration
+	dropped  int64
+
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewSlackAlerter returns a SlackAlerter that posts to the given incoming
+// webhook URL under username.
+func NewSlackAlerter(channel, username string, opts SlackOptions) *SlackAlerter {
+	queueSize := opts.QueueSize
+	if queueSize <= 0 {
+		queueSize = 256
+	}
+
+	sink := NewSlackSink(channel, opts)
+	sink.username = username
+
+	a := &SlackAlerter{
+		sink:     sink,
+		queue:    make(chan attachment, queueSize),
+		done:     make(chan struct{}),
+		coalesce: opts.CoalesceWindow,
+	}
+	a.wg.Add(1)
+	go a.run()
+	return a
+}
+
+// Write implements Sink by enqueuing msg for the background goroutine to
+// post. It never blocks: once the queue is full, Write drops the message
+// and returns an error instead.
+func (a *SlackAlerter) Write(sev Severity, ts time.Time, file string, line int, msg string) error {
+	a.mu.Lock()
+	closed := a.closed
+	a.mu.Unlock()
+	if closed {
+		return fmt.Errorf("logger: SlackAlerter is closed")
+	}
+
+	att := attachment{
+		Title: fmt.Sprintf("%s: %s:%d", sev, file, line),
+		Color: a.sink.colors[sev],
+		Text:  msg,
+	}
+	select {
+	case a.queue <- att:
+		return nil
+	default:
+		atomic.AddInt64(&a.dropped, 1)
+		return fmt.Errorf("logger: Slack alert queue full, dropping message")
+	}
+}
+
+// Errors reports how many messages have been dropped because the queue was
+// full.
+func (a *SlackAlerter) Errors() int64 {
+	return atomic.LoadInt64(&a.dropped)
+}
+
+// Flush is a no-op: SlackAlerter posts asynchronously, and Close is what
+// guarantees the queue has drained.
+func (a *SlackAlerter) Flush() error { return nil }
+
+// Close stops accepting new messages, waits for whatever is already queued
+// to be posted, and returns once the background goroutine has exited. Any
+// send already in flight has its context canceled, so Close doesn't wait
+// out a pending retry's backoff or timeout.
+func (a *SlackAlerter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	a.mu.Unlock()
+
+	close(a.done)
+	a.wg.Wait()
+	return nil
+}
+
+func (a *SlackAlerter) run() {
+	defer a.wg.Done()
+	for {
+		batch, ok := a.nextBatch()
+		if len(batch) > 0 {
+			a.send(batch)
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// nextBatch blocks for the first message of the next batch, then, if
+// CoalesceWindow is set, keeps collecting whatever else arrives within
+// that window so a burst becomes one Slack post. It reports ok=false once
+// Close has been called and the queue has been drained.
+func (a *SlackAlerter) nextBatch() (batch []attachment, ok bool) {
+	select {
+	case att := <-a.queue:
+		batch = append(batch, att)
+	case <-a.done:
+		drained := a.drainQueue()
+		return drained, len(drained) > 0
+	}
+
+	// Grab whatever else is already sitting in the queue before starting
+	// the coalesce timer, so a burst that was fully enqueued before this
+	// goroutine got scheduled doesn't depend on winning a timing race.
+	batch = append(batch, a.drainQueue()...)
+
+	if a.coalesce <= 0 {
+		return batch, true
+	}
+
+	deadline := time.NewTimer(a.coalesce)
+	defer deadline.Stop()
+	for {
+		select {
+		case att := <-a.queue:
+			batch = append(batch, att)
+		case <-deadline.C:
+			return batch, true
+		case <-a.done:
+			// Closing: send whatever we have rather than waiting out the
+			// rest of the coalesce window for messages that can't come.
+			return append(batch, a.drainQueue()...), true
+		}
+	}
+}
+
+// drainQueue returns every message currently waiting in the queue without
+// blocking.
+func (a *SlackAlerter) drainQueue() []attachment {
+	var batch []attachment
+	for {
+		select {
+		case att := <-a.queue:
+			batch = append(batch, att)
+		default:
+			return batch
+		}
+	}
+}
+
+// send posts batch, retrying with exponential backoff (seeded from Slack's
+// Retry-After header) if Slack rate-limits the request.
+func (a *SlackAlerter) send(batch []attachment) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	select {
+	case <-a.done:
+		// Close already happened and this batch is the final drain it's
+		// waiting on; let it run to completion rather than aborting it.
+	default:
+		// Tie ctx to Close so a Fatal-path shutdown doesn't wait out a
+		// retry already in flight: once a.done closes, cancel aborts the
+		// in-flight POST and any pending backoff sleep.
+		go func() {
+			select {
+			case <-a.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	backoff := time.Second
+	for attempt := 0; attempt < 5; attempt++ {
+		err := a.sink.postAttachments(ctx, batch)
+		if err == nil {
+			return
+		}
+
+		var rateLimit *slackRateLimitError
+		if errors.As(err, &rateLimit) && rateLimit.retryAfter > backoff {
+			backoff = rateLimit.retryAfter
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSlackAlerterPostsAndCoalesces(t *testing.T) {
+	var mu sync.Mutex
+	var posts []struct {
+		Attachments []attachment `json:"attachments"`
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Attachments []attachment `json:"attachments"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding request body: %v", err)
+		}
+		mu.Lock()
+		posts = append(posts, payload)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	a := NewSlackAlerter(srv.URL, "bot", SlackOptions{CoalesceWindow: 50 * time.Millisecond})
+
+	a.Write(SeverityError, time.Now(), "x.go", 1, "first")
+	a.Write(SeverityError, time.Now(), "x.go", 2, "second")
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(posts) != 1 {
+		t.Fatalf("got %d Slack posts, want 1 coalesced post: %+v", len(posts), posts)
+	}
+	if len(posts[0].Attachments) != 2 {
+		t.Errorf("got %d attachments, want 2: %+v", len(posts[0].Attachments), posts[0].Attachments)
+	}
+}
+
+func TestSlackAlerterDropsWhenQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	a := NewSlackAlerter(srv.URL, "bot", SlackOptions{QueueSize: 1})
+	defer func() {
+		close(block)
+		a.Close()
+	}()
+
+	// The first write is picked up by run() immediately and blocks on the
+	// handler; the next QueueSize writes fill the channel, and the one
+	// after that must be dropped.
+	for i := 0; i < 3; i++ {
+		a.Write(SeverityError, time.Now(), "x.go", i, "msg")
+	}
+
+	if got := a.Errors(); got == 0 {
+		t.Errorf("Errors() = 0, want at least one dropped message")
+	}
+}
+
+func TestSlackAlerterCloseCancelsRetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	a := NewSlackAlerter(srv.URL, "bot", SlackOptions{})
+	a.Write(SeverityError, time.Now(), "x.go", 1, "msg")
+
+	// Give run() a moment to pick up the message and start retrying against
+	// the 429, then make sure Close doesn't wait out the backoff.
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() { done <- a.Close() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return promptly while a retry was in flight")
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Options carries the logging options that have been added since Init's
+// original four-argument signature was fixed. Pass them to InitWithOptions.
+type Options struct {
+	// Structured makes InfoS and ErrorS emit JSON records instead of the
+	// default logfmt-style "key=value" pairs.
+	Structured bool
+}
+
+// InitWithOptions is Init plus the additional settings in Options.
+func InitWithOptions(name string, verbose, systemLog bool, logFile io.Writer, opts Options) *Logger {
+	l := Init(name, verbose, systemLog, logFile)
+	l.structured = opts.Structured
+	return l
+}
+
+// With returns a child logger that writes to the same sinks as l but
+// prepends keysAndValues to the pairs passed to every future InfoS/ErrorS
+// call. Pairs accumulate across repeated With calls.
+func (l *Logger) With(keysAndValues ...interface{}) *Logger {
+	l.mu.Lock()
+	sinks := l.sinks
+	l.mu.Unlock()
+
+	kv := make([]interface{}, 0, len(l.kv)+len(keysAndValues))
+	kv = append(kv, l.kv...)
+	kv = append(kv, keysAndValues...)
+
+	return &Logger{
+		sinks:       sinks,
+		initialized: l.initialized,
+		structured:  l.structured,
+		kv:          kv,
+	}
+}
+
+// InfoS logs a structured record at INFO severity: msg plus l's bound pairs
+// (from With) followed by keysAndValues, logfmt-encoded by default or
+// JSON-encoded if l was created with Options{Structured: true}.
+func (l *Logger) InfoS(msg string, keysAndValues ...interface{}) {
+	l.output(SeverityInfo, l.formatStructured(msg, keysAndValues))
+}
+
+// ErrorS logs a structured record at ERROR severity: msg plus l's bound
+// pairs (from With) followed by keysAndValues, logfmt-encoded by default or
+// JSON-encoded if l was created with Options{Structured: true}.
+func (l *Logger) ErrorS(msg string, keysAndValues ...interface{}) {
+	l.output(SeverityError, l.formatStructured(msg, keysAndValues))
+}
+
+// With uses the default logger. See Logger.With.
+func With(keysAndValues ...interface{}) *Logger {
+	return defaultLogger.With(keysAndValues...)
+}
+
+// InfoS uses the default logger. See Logger.InfoS.
+func InfoS(msg string, keysAndValues ...interface{}) {
+	defaultLogger.output(SeverityInfo, defaultLogger.formatStructured(msg, keysAndValues))
+}
+
+// ErrorS uses the default logger. See Logger.ErrorS.
+func ErrorS(msg string, keysAndValues ...interface{}) {
+	defaultLogger.output(SeverityError, defaultLogger.formatStructured(msg, keysAndValues))
+}
+
+func (l *Logger) formatStructured(msg string, keysAndValues []interface{}) string {
+	kv := make([]interface{}, 0, len(l.kv)+len(keysAndValues))
+	kv = append(kv, l.kv...)
+	kv = append(kv, keysAndValues...)
+
+	if l.structured {
+		return formatJSON(msg, kv)
+	}
+	return formatLogfmt(msg, kv)
+}
+
+// normalizeValue turns v into something structured output can render
+// naturally: strings pass through, errors and fmt.Stringer become their
+// string form, and everything else is left untouched so JSON encodes it
+// with its own type and logfmt falls back to a %+v rendering.
+func normalizeValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case string:
+		return x
+	case error:
+		return x.Error()
+	case fmt.Stringer:
+		return x.String()
+	default:
+		return x
+	}
+}
+
+func keyName(k interface{}) string {
+	if s, ok := k.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", k)
+}
+
+// forEachPair walks keysAndValues in key/value pairs, calling fn for each.
+// A trailing key with no value is reported to fn as key "!BADKEY" paired
+// with that dangling value, per klog's structured logging convention.
+func forEachPair(keysAndValues []interface{}, fn func(key string, val interface{})) {
+	i := 0
+	for ; i+1 < len(keysAndValues); i += 2 {
+		fn(keyName(keysAndValues[i]), keysAndValues[i+1])
+	}
+	if i < len(keysAndValues) {
+		fn("!BADKEY", keysAndValues[i])
+	}
+}
+
+func logfmtValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		if s == "" || strings.ContainsAny(s, " \t\"=") {
+			return strconv.Quote(s)
+		}
+		return s
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+func formatLogfmt(msg string, kv []interface{}) string {
+	var b strings.Builder
+	b.WriteString("msg=")
+	b.WriteString(logfmtValue(msg))
+
+	forEachPair(kv, func(key string, val interface{}) {
+		b.WriteByte(' ')
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(normalizeValue(val)))
+	})
+	return b.String()
+}
+
+func formatJSON(msg string, kv []interface{}) string {
+	keys := []string{"msg"}
+	fields := map[string]interface{}{"msg": msg}
+
+	forEachPair(kv, func(key string, val interface{}) {
+		if _, exists := fields[key]; !exists {
+			keys = append(keys, key)
+		}
+		fields[key] = normalizeValue(val)
+	})
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		keyJSON, _ := json.Marshal(key)
+		valJSON, err := json.Marshal(fields[key])
+		if err != nil {
+			valJSON, _ = json.Marshal(fmt.Sprintf("%+v", fields[key]))
+		}
+		b.Write(keyJSON)
+		b.WriteByte(':')
+		b.Write(valJSON)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
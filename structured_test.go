@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInfoSLogfmt(t *testing.T) {
+	var buf bytes.Buffer
+	l := Init("testInfoS", false, false, &buf)
+
+	l.InfoS("starting up", "port", 8080, "err", errors.New("boom"))
+
+	out := buf.String()
+	for _, want := range []string{`msg="starting up"`, `port=8080`, `err=boom`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q missing %q", out, want)
+		}
+	}
+}
+
+func TestInfoSBadKey(t *testing.T) {
+	var buf bytes.Buffer
+	l := Init("testInfoSBadKey", false, false, &buf)
+
+	l.InfoS("oops", "dangling")
+
+	if out := buf.String(); !strings.Contains(out, "!BADKEY=dangling") {
+		t.Errorf("output %q missing !BADKEY handling", out)
+	}
+}
+
+func TestInfoSJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := InitWithOptions("testInfoSJSON", false, false, &buf, Options{Structured: true})
+
+	l.InfoS("starting up", "port", 8080)
+
+	if out := buf.String(); !strings.Contains(out, `"msg":"starting up"`) || !strings.Contains(out, `"port":8080`) {
+		t.Errorf("output %q not valid structured JSON", out)
+	}
+}
+
+func TestPackageLevelInfoSReportsCallerLine(t *testing.T) {
+	old := defaultLogger
+	defer func() { defaultLogger = old }()
+
+	var buf bytes.Buffer
+	defaultLogger = &Logger{initialized: true}
+	defaultLogger.AddSink(newWriterSink(&buf), SeverityInfo)
+
+	InfoS("package-level call")
+
+	out := buf.String()
+	if strings.Contains(out, " structured.go:") {
+		t.Errorf("output %q reports structured.go instead of the caller", out)
+	}
+	if !strings.Contains(out, " structured_test.go:") {
+		t.Errorf("output %q does not report the InfoS call site: %s", out, out)
+	}
+}
+
+func TestWithBindsPairs(t *testing.T) {
+	var buf bytes.Buffer
+	l := Init("testWith", false, false, &buf)
+
+	child := l.With("requestID", "abc123")
+	child.InfoS("handled", "status", 200)
+
+	if out := buf.String(); !strings.Contains(out, "requestID=abc123") || !strings.Contains(out, "status=200") {
+		t.Errorf("output %q missing bound or call-site pairs", out)
+	}
+}
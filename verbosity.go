@@ -0,0 +1,218 @@
+/*
+Copyright 2016 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Level is a verbosity level, as used by V. Higher values are more verbose.
+type Level int32
+
+// verbosity is the global -v setting: V(level) is enabled whenever level is
+// less than or equal to verbosity, unless a -vmodule pattern overrides it
+// for the calling file.
+var verbosity Level
+
+// SetV sets the global verbosity level used by V.
+func SetV(level Level) {
+	atomic.StoreInt32((*int32)(&verbosity), int32(level))
+	vcache.clear()
+}
+
+func (l Level) get() Level {
+	return Level(atomic.LoadInt32((*int32)(&verbosity)))
+}
+
+// moduleSpec is one "pattern=level" entry parsed from a -vmodule string.
+type moduleSpec struct {
+	pattern string
+	level   Level
+}
+
+// vmodule holds the parsed -vmodule patterns that override verbosity for
+// matching source files.
+var vmodule moduleSpecs
+
+type moduleSpecs struct {
+	mu    sync.Mutex
+	specs []moduleSpec
+}
+
+// SetVModule parses a glog-style vmodule spec, e.g. "file1=2,pkg/foo=3", and
+// installs it in place of any previous one. Patterns are matched against the
+// caller's file using filepath.Match; a pattern containing a "/" is matched
+// against the trailing path segments of the caller's file (so "pkg/foo"
+// matches ".../anything/pkg/foo.go" regardless of where the file actually
+// lives) with the ".go" suffix stripped, otherwise it is matched against
+// just the base filename, also with its suffix stripped.
+func SetVModule(spec string) error {
+	var specs []moduleSpec
+	for _, entry := range strings.Split(spec, ",") {
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("logger: malformed vmodule entry %q", entry)
+		}
+		level, err := strconv.ParseInt(parts[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("logger: malformed vmodule level in %q: %v", entry, err)
+		}
+		specs = append(specs, moduleSpec{pattern: parts[0], level: Level(level)})
+	}
+
+	vmodule.mu.Lock()
+	vmodule.specs = specs
+	vmodule.mu.Unlock()
+	vcache.clear()
+	return nil
+}
+
+// match returns the override level for file, and whether a pattern matched.
+func (m *moduleSpecs) match(file string) (Level, bool) {
+	m.mu.Lock()
+	specs := m.specs
+	m.mu.Unlock()
+
+	if len(specs) == 0 {
+		return 0, false
+	}
+
+	base := filepath.Base(file)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	full := strings.TrimSuffix(file, filepath.Ext(file))
+	fullParts := strings.Split(filepath.ToSlash(full), "/")
+
+	for _, s := range specs {
+		if !strings.Contains(s.pattern, "/") {
+			if ok, _ := filepath.Match(s.pattern, base); ok {
+				return s.level, true
+			}
+			continue
+		}
+
+		patternParts := strings.Split(s.pattern, "/")
+		if len(patternParts) > len(fullParts) {
+			continue
+		}
+		trailing := strings.Join(fullParts[len(fullParts)-len(patternParts):], "/")
+		if ok, _ := filepath.Match(s.pattern, trailing); ok {
+			return s.level, true
+		}
+	}
+	return 0, false
+}
+
+// vcache memoizes the effective verbosity level for a call site (keyed by
+// its program counter) so repeated V() calls on a hot path don't re-walk the
+// vmodule patterns; runtime.Caller still runs on every call to find the
+// program counter. It is invalidated
+// whenever SetV or SetVModule changes the configuration.
+var vcache verbosityCache
+
+type verbosityCache struct {
+	gen   int32
+	cache sync.Map // uintptr (pc) -> cacheEntry
+}
+
+type cacheEntry struct {
+	gen   int32
+	level Level
+}
+
+func (c *verbosityCache) clear() {
+	atomic.AddInt32(&c.gen, 1)
+}
+
+func (c *verbosityCache) get(pc uintptr, file string) Level {
+	gen := atomic.LoadInt32(&c.gen)
+	if e, ok := c.cache.Load(pc); ok {
+		if entry := e.(cacheEntry); entry.gen == gen {
+			return entry.level
+		}
+	}
+
+	level := verbosity.get()
+	if v, ok := vmodule.match(file); ok {
+		level = v
+	}
+	c.cache.Store(pc, cacheEntry{gen: gen, level: level})
+	return level
+}
+
+// callerVerbosity reports the effective verbosity level for the caller
+// skip frames up the stack, where skip=1 is the function calling
+// callerVerbosity. It must be called directly from each V entry point so
+// the skip count stays accurate.
+func callerVerbosity(skip int) Level {
+	pc, file, _, ok := runtime.Caller(skip)
+	if !ok {
+		return verbosity.get()
+	}
+	return vcache.get(pc, file)
+}
+
+// Verbose is returned by V and Logger.V. Its Info/Infoln/Infof methods are
+// no-ops unless the verbosity check that produced it was satisfied.
+type Verbose struct {
+	enabled bool
+	logger  *Logger
+}
+
+// Info logs with the INFO severity if v is enabled.
+// Arguments are handled in the manner of fmt.Print.
+func (v Verbose) Info(args ...interface{}) {
+	if v.enabled {
+		v.logger.output(SeverityInfo, fmt.Sprint(args...))
+	}
+}
+
+// Infoln logs with the INFO severity if v is enabled.
+// Arguments are handled in the manner of fmt.Println.
+func (v Verbose) Infoln(args ...interface{}) {
+	if v.enabled {
+		v.logger.output(SeverityInfo, fmt.Sprintln(args...))
+	}
+}
+
+// Infof logs with the INFO severity if v is enabled.
+// Arguments are handled in the manner of fmt.Printf.
+func (v Verbose) Infof(format string, args ...interface{}) {
+	if v.enabled {
+		v.logger.output(SeverityInfo, fmt.Sprintf(format, args...))
+	}
+}
+
+// V reports whether verbosity at the given level is enabled for the calling
+// source file, taking any -vmodule override into account, and returns a
+// Verbose value for conditionally logging at that level.
+func (l *Logger) V(level Level) Verbose {
+	return Verbose{logger: l, enabled: level <= callerVerbosity(2)}
+}
+
+// V uses the default logger. It reports whether verbosity at the given
+// level is enabled for the calling source file, taking any -vmodule
+// override into account, and returns a Verbose value for conditionally
+// logging at that level.
+func V(level Level) Verbose {
+	return Verbose{logger: defaultLogger, enabled: level <= callerVerbosity(2)}
+}
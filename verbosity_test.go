@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"bytes"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestV(t *testing.T) {
+	defer SetV(0)
+
+	var buf bytes.Buffer
+	l := Init("testV", false, false, &buf)
+
+	SetV(1)
+	l.V(2).Info("too verbose")
+	l.V(1).Info("just right")
+
+	out := buf.String()
+	if strings.Contains(out, "too verbose") {
+		t.Errorf("V(2) logged at verbosity 1: %q", out)
+	}
+	if !strings.Contains(out, "just right") {
+		t.Errorf("V(1) did not log at verbosity 1: %q", out)
+	}
+}
+
+func TestSetVModule(t *testing.T) {
+	defer SetVModule("")
+	defer SetV(0)
+
+	if err := SetVModule("verbosity_test=3"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	l := Init("testVModule", false, false, &buf)
+
+	l.V(2).Info("enabled by vmodule")
+
+	if out := buf.String(); !strings.Contains(out, "enabled by vmodule") {
+		t.Errorf("V(2) did not log under vmodule override: %q", out)
+	}
+}
+
+func TestSetVModuleSlashPattern(t *testing.T) {
+	defer SetVModule("")
+	defer SetV(0)
+
+	// Build a pattern from the trailing "<dir>/verbosity_test" segments of
+	// this file's own path, since the leading part of the absolute path
+	// varies by checkout location.
+	_, file, _, _ := runtime.Caller(0)
+	pattern := filepath.Base(filepath.Dir(file)) + "/verbosity_test"
+
+	if err := SetVModule(pattern + "=3"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	l := Init("testVModuleSlash", false, false, &buf)
+
+	l.V(2).Info("enabled by slash vmodule")
+
+	if out := buf.String(); !strings.Contains(out, "enabled by slash vmodule") {
+		t.Errorf("V(2) did not log under slash vmodule override: %q", out)
+	}
+}